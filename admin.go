@@ -0,0 +1,226 @@
+package main
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"net/http"
+	"net/url"
+
+	"github.com/gorilla/mux"
+	"github.com/harshit0410/LB/backend"
+	"github.com/harshit0410/LB/healthcheck"
+)
+
+// registerAdminRoutes wires the /admin/* API onto router, behind
+// requireAdminAuth.
+func registerAdminRoutes(router *mux.Router) {
+	admin := router.PathPrefix("/admin").Subrouter()
+	admin.HandleFunc("/backends", requireAdminAuth(adminListBackends)).Methods("GET")
+	admin.HandleFunc("/backends", requireAdminAuth(adminAddBackend)).Methods("POST")
+	admin.HandleFunc("/backends/{id}", requireAdminAuth(adminDeleteBackend)).Methods("DELETE")
+	admin.HandleFunc("/backends/{id}", requireAdminAuth(adminPatchBackend)).Methods("PATCH")
+	admin.HandleFunc("/stats", requireAdminAuth(adminStats)).Methods("GET")
+	admin.HandleFunc("/config", requireAdminAuth(adminGetConfig)).Methods("GET")
+	admin.HandleFunc("/config", requireAdminAuth(adminUpdateConfig)).Methods("PUT")
+}
+
+// requireAdminAuth enforces the active Config's Admin settings on next: a
+// bearer token, HTTP basic auth, or no check at all if neither is
+// configured.
+func requireAdminAuth(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		auth := getConfig().Admin
+
+		if auth.Token != "" {
+			if constantTimeEqual(r.Header.Get("Authorization"), "Bearer "+auth.Token) {
+				next(w, r)
+				return
+			}
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		if auth.Username != "" {
+			user, pass, ok := r.BasicAuth()
+			if ok && constantTimeEqual(user, auth.Username) && constantTimeEqual(pass, auth.Password) {
+				next(w, r)
+				return
+			}
+			w.Header().Set("WWW-Authenticate", `Basic realm="admin"`)
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		next(w, r)
+	}
+}
+
+func constantTimeEqual(a, b string) bool {
+	return subtle.ConstantTimeCompare([]byte(a), []byte(b)) == 1
+}
+
+type backendView struct {
+	URL          string `json:"url"`
+	Alive        bool   `json:"alive"`
+	Draining     bool   `json:"draining"`
+	Weight       int    `json:"weight"`
+	InFlight     int64  `json:"in_flight"`
+	BreakerState string `json:"breaker_state"`
+	SuccessCount int64  `json:"success_count"`
+	ErrorCount   int64  `json:"error_count"`
+}
+
+func toBackendView(b *backend.Backend) backendView {
+	stats := b.Stats()
+	breakerState := "closed"
+	if b.Breaker != nil {
+		breakerState = b.Breaker.State().String()
+	}
+	return backendView{
+		URL:          b.URL.String(),
+		Alive:        b.IsAlive(),
+		Draining:     b.IsDraining(),
+		Weight:       b.GetWeight(),
+		InFlight:     stats.ActiveConns,
+		BreakerState: breakerState,
+		SuccessCount: stats.Success,
+		ErrorCount:   stats.Errors,
+	}
+}
+
+func adminListBackends(w http.ResponseWriter, r *http.Request) {
+	backends := serverPool.Backends()
+	views := make([]backendView, 0, len(backends))
+	for _, b := range backends {
+		views = append(views, toBackendView(b))
+	}
+	writeJSON(w, http.StatusOK, views)
+}
+
+type addBackendRequest struct {
+	URL    string              `json:"url"`
+	Weight int                 `json:"weight"`
+	Proto  string              `json:"proto"`
+	Root   string              `json:"root"`
+	Health *healthcheck.Config `json:"health"`
+}
+
+func adminAddBackend(w http.ResponseWriter, r *http.Request) {
+	var req addBackendRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.URL == "" {
+		http.Error(w, "url is required", http.StatusBadRequest)
+		return
+	}
+	if serverPool.FindBackend(req.URL) != nil {
+		http.Error(w, "backend already exists", http.StatusConflict)
+		return
+	}
+
+	b, err := newBackend(req.URL, req.Weight, UpstreamConfig{Proto: req.Proto, Root: req.Root}, req.Health)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	serverPool.AddBackend(b)
+
+	writeJSON(w, http.StatusCreated, toBackendView(b))
+}
+
+func adminDeleteBackend(w http.ResponseWriter, r *http.Request) {
+	target, err := backendIDFromRequest(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if !serverPool.RemoveBackend(target) {
+		http.Error(w, "backend not found", http.StatusNotFound)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+type patchBackendRequest struct {
+	Draining *bool `json:"draining"`
+	Weight   *int  `json:"weight"`
+}
+
+func adminPatchBackend(w http.ResponseWriter, r *http.Request) {
+	target, err := backendIDFromRequest(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	b := serverPool.FindBackend(target)
+	if b == nil {
+		http.Error(w, "backend not found", http.StatusNotFound)
+		return
+	}
+
+	var req patchBackendRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if req.Draining != nil {
+		b.SetDraining(*req.Draining)
+	}
+	if req.Weight != nil {
+		b.SetWeight(*req.Weight)
+	}
+
+	writeJSON(w, http.StatusOK, toBackendView(b))
+}
+
+func backendIDFromRequest(r *http.Request) (string, error) {
+	return url.QueryUnescape(mux.Vars(r)["id"])
+}
+
+type statsView struct {
+	URL              string  `json:"url"`
+	SuccessCount     int64   `json:"success_count"`
+	ErrorCount       int64   `json:"error_count"`
+	InFlight         int64   `json:"in_flight"`
+	LatencyBucketsMs []int64 `json:"latency_buckets_ms"` // upper bound per bucket, last is +Inf (-1)
+	LatencyCounts    []int64 `json:"latency_counts"`     // request count per bucket
+}
+
+func adminStats(w http.ResponseWriter, r *http.Request) {
+	backends := serverPool.Backends()
+	views := make([]statsView, 0, len(backends))
+	for _, b := range backends {
+		stats := b.Stats()
+		views = append(views, statsView{
+			URL:              b.URL.String(),
+			SuccessCount:     stats.Success,
+			ErrorCount:       stats.Errors,
+			InFlight:         stats.ActiveConns,
+			LatencyBucketsMs: stats.LatencyBucketsMs[:],
+			LatencyCounts:    stats.LatencyCounts[:],
+		})
+	}
+	writeJSON(w, http.StatusOK, views)
+}
+
+func adminGetConfig(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, getConfig())
+}
+
+func adminUpdateConfig(w http.ResponseWriter, r *http.Request) {
+	var inputConfig Config
+	if err := json.NewDecoder(r.Body).Decode(&inputConfig); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	persistConfig(inputConfig)
+	applyConfigDiff(inputConfig)
+
+	writeJSON(w, http.StatusOK, inputConfig)
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("content-type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
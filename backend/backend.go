@@ -0,0 +1,112 @@
+package backend
+
+import (
+	"net/http"
+	"net/url"
+	"sync"
+	"sync/atomic"
+
+	"github.com/harshit0410/LB/healthcheck"
+)
+
+// Backend holds the state for a single upstream target. Upstream does
+// the actual proxying and may speak HTTP, FastCGI, gRPC/h2c, or anything
+// else that can be driven from an http.Handler; LB and the admin API
+// only ever deal with Backend, not the wire format behind it.
+type Backend struct {
+	URL      *url.URL
+	Alive    bool
+	Draining bool
+	Weight   int
+	Upstream http.Handler
+	Breaker  *CircuitBreaker
+
+	mux          sync.RWMutex
+	connections  int64
+	healthConfig *healthcheck.Config
+
+	successCount   int64
+	errorCount     int64
+	latencyBuckets [numLatencyBuckets]int64
+}
+
+// SetAlive updates the alive status of the backend.
+func (b *Backend) SetAlive(alive bool) {
+	b.mux.Lock()
+	b.Alive = alive
+	b.mux.Unlock()
+}
+
+// IsAlive returns true if the backend is currently marked alive.
+func (b *Backend) IsAlive() (alive bool) {
+	b.mux.RLock()
+	alive = b.Alive
+	b.mux.RUnlock()
+	return
+}
+
+// SetDraining marks the backend as draining: no new requests are routed
+// to it, but requests already in flight are left to complete.
+func (b *Backend) SetDraining(draining bool) {
+	b.mux.Lock()
+	b.Draining = draining
+	b.mux.Unlock()
+}
+
+// IsDraining returns true if the backend is currently draining.
+func (b *Backend) IsDraining() (draining bool) {
+	b.mux.RLock()
+	draining = b.Draining
+	b.mux.RUnlock()
+	return
+}
+
+// SetWeight updates the backend's weight, as used by
+// WeightedRoundRobinPolicy. Safe to call while requests are being routed.
+func (b *Backend) SetWeight(weight int) {
+	b.mux.Lock()
+	b.Weight = weight
+	b.mux.Unlock()
+}
+
+// GetWeight returns the backend's current weight.
+func (b *Backend) GetWeight() (weight int) {
+	b.mux.RLock()
+	weight = b.Weight
+	b.mux.RUnlock()
+	return
+}
+
+// SetHealthConfig overrides this backend's active health-check config,
+// independent of the pool-wide default. Pass nil to go back to
+// inheriting the pool's config.
+func (b *Backend) SetHealthConfig(cfg *healthcheck.Config) {
+	b.mux.Lock()
+	b.healthConfig = cfg
+	b.mux.Unlock()
+}
+
+// HealthConfig returns this backend's health-check override, or nil if
+// it inherits the pool's config.
+func (b *Backend) HealthConfig() *healthcheck.Config {
+	b.mux.RLock()
+	defer b.mux.RUnlock()
+	return b.healthConfig
+}
+
+// IncConnections records a new in-flight request to this backend and
+// returns the updated count.
+func (b *Backend) IncConnections() int64 {
+	return atomic.AddInt64(&b.connections, 1)
+}
+
+// DecConnections releases an in-flight request slot.
+func (b *Backend) DecConnections() {
+	atomic.AddInt64(&b.connections, -1)
+}
+
+// ActiveConnections returns the number of requests currently in flight
+// to this backend.
+func (b *Backend) ActiveConnections() int64 {
+	return atomic.LoadInt64(&b.connections)
+}
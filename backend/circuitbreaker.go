@@ -0,0 +1,138 @@
+package backend
+
+import (
+	"sync"
+	"time"
+)
+
+// BreakerState is the circuit-breaker state for a backend.
+type BreakerState int32
+
+const (
+	// StateClosed routes requests normally.
+	StateClosed BreakerState = iota
+	// StateOpen stops routing new requests until ResetTimeout elapses.
+	StateOpen
+	// StateHalfOpen allows probe requests through to test recovery.
+	StateHalfOpen
+)
+
+func (s BreakerState) String() string {
+	switch s {
+	case StateClosed:
+		return "closed"
+	case StateOpen:
+		return "open"
+	case StateHalfOpen:
+		return "half-open"
+	default:
+		return "unknown"
+	}
+}
+
+// CircuitBreaker passively tracks proxied-request outcomes for a backend
+// in a sliding time window. It trips from closed to open once failures
+// within Window reach UnhealthyThreshold, and recovers through half-open
+// once HealthyThreshold consecutive requests there succeed.
+type CircuitBreaker struct {
+	UnhealthyThreshold int
+	HealthyThreshold   int
+	Window             time.Duration
+	ResetTimeout       time.Duration
+
+	mux        sync.Mutex
+	state      BreakerState
+	failures   []time.Time
+	halfOpenOK int
+	openedAt   time.Time
+}
+
+// NewCircuitBreaker builds a closed breaker with the given thresholds.
+// window bounds how far back passive failures are counted; resetTimeout
+// is how long the breaker stays open before allowing a half-open probe.
+func NewCircuitBreaker(unhealthyThreshold, healthyThreshold int, window, resetTimeout time.Duration) *CircuitBreaker {
+	return &CircuitBreaker{
+		UnhealthyThreshold: unhealthyThreshold,
+		HealthyThreshold:   healthyThreshold,
+		Window:             window,
+		ResetTimeout:       resetTimeout,
+	}
+}
+
+// Allow reports whether a request may currently be routed to the
+// backend, flipping an expired open breaker to half-open first.
+func (cb *CircuitBreaker) Allow() bool {
+	cb.mux.Lock()
+	defer cb.mux.Unlock()
+
+	if cb.state == StateOpen && time.Since(cb.openedAt) >= cb.ResetTimeout {
+		cb.state = StateHalfOpen
+		cb.halfOpenOK = 0
+	}
+	return cb.state != StateOpen
+}
+
+// State returns the breaker's current state.
+func (cb *CircuitBreaker) State() BreakerState {
+	cb.mux.Lock()
+	defer cb.mux.Unlock()
+	return cb.state
+}
+
+// RecordSuccess registers a successful proxied request.
+func (cb *CircuitBreaker) RecordSuccess() {
+	cb.mux.Lock()
+	defer cb.mux.Unlock()
+
+	switch cb.state {
+	case StateHalfOpen:
+		cb.halfOpenOK++
+		if cb.halfOpenOK >= cb.HealthyThreshold {
+			cb.reset()
+		}
+	case StateClosed:
+		cb.trimFailures()
+	}
+}
+
+// RecordFailure registers a failed proxied request, tripping the breaker
+// open if failures within Window reach UnhealthyThreshold, or immediately
+// if the failure happened during a half-open probe.
+func (cb *CircuitBreaker) RecordFailure() {
+	cb.mux.Lock()
+	defer cb.mux.Unlock()
+
+	if cb.state == StateHalfOpen {
+		cb.trip()
+		return
+	}
+
+	cb.failures = append(cb.failures, time.Now())
+	cb.trimFailures()
+	if len(cb.failures) >= cb.UnhealthyThreshold {
+		cb.trip()
+	}
+}
+
+func (cb *CircuitBreaker) trimFailures() {
+	cutoff := time.Now().Add(-cb.Window)
+	kept := cb.failures[:0]
+	for _, t := range cb.failures {
+		if t.After(cutoff) {
+			kept = append(kept, t)
+		}
+	}
+	cb.failures = kept
+}
+
+func (cb *CircuitBreaker) trip() {
+	cb.state = StateOpen
+	cb.openedAt = time.Now()
+	cb.failures = nil
+}
+
+func (cb *CircuitBreaker) reset() {
+	cb.state = StateClosed
+	cb.failures = nil
+	cb.halfOpenOK = 0
+}
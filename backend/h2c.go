@@ -0,0 +1,25 @@
+package backend
+
+import (
+	"context"
+	"crypto/tls"
+	"net"
+	"net/http"
+
+	"golang.org/x/net/http2"
+)
+
+// NewH2CTransport builds an http.RoundTripper that speaks HTTP/2 in
+// cleartext (h2c) to a backend, for proxying to gRPC servers that don't
+// terminate TLS themselves. cfg's DialTimeout still applies; the other
+// TransportConfig fields don't have an http2.Transport equivalent and are
+// ignored here.
+func NewH2CTransport(cfg TransportConfig) http.RoundTripper {
+	dialer := &net.Dialer{Timeout: cfg.DialTimeout}
+	return &http2.Transport{
+		AllowHTTP: true,
+		DialTLSContext: func(ctx context.Context, network, addr string, _ *tls.Config) (net.Conn, error) {
+			return dialer.DialContext(ctx, network, addr)
+		},
+	}
+}
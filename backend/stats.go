@@ -0,0 +1,65 @@
+package backend
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// latencyBucketsMs are the inclusive upper bounds, in milliseconds, of
+// each histogram bucket. A request slower than the last bound falls into
+// an implicit final overflow bucket, hence numLatencyBuckets being one
+// larger than len(latencyBucketsMs).
+var latencyBucketsMs = [6]int64{10, 50, 100, 500, 1000, 5000}
+
+const numLatencyBuckets = len(latencyBucketsMs) + 1
+
+// Stats is a point-in-time snapshot of a backend's counters, safe to read
+// while requests are still being proxied to it.
+type Stats struct {
+	Success          int64
+	Errors           int64
+	ActiveConns      int64
+	LatencyBucketsMs [numLatencyBuckets]int64 // upper bound per bucket; last is +Inf
+	LatencyCounts    [numLatencyBuckets]int64 // request count per bucket
+}
+
+// RecordSuccess records a successfully proxied request and its latency.
+func (b *Backend) RecordSuccess(latency time.Duration) {
+	atomic.AddInt64(&b.successCount, 1)
+	b.recordLatency(latency)
+}
+
+// RecordError records a failed proxied request and its latency.
+func (b *Backend) RecordError(latency time.Duration) {
+	atomic.AddInt64(&b.errorCount, 1)
+	b.recordLatency(latency)
+}
+
+func (b *Backend) recordLatency(latency time.Duration) {
+	ms := latency.Milliseconds()
+	for i, bound := range latencyBucketsMs {
+		if ms <= bound {
+			atomic.AddInt64(&b.latencyBuckets[i], 1)
+			return
+		}
+	}
+	atomic.AddInt64(&b.latencyBuckets[numLatencyBuckets-1], 1)
+}
+
+// Stats returns a snapshot of this backend's success/error counts,
+// in-flight request count, and latency histogram.
+func (b *Backend) Stats() Stats {
+	s := Stats{
+		Success:     atomic.LoadInt64(&b.successCount),
+		Errors:      atomic.LoadInt64(&b.errorCount),
+		ActiveConns: b.ActiveConnections(),
+	}
+	for i := range latencyBucketsMs {
+		s.LatencyBucketsMs[i] = latencyBucketsMs[i]
+	}
+	s.LatencyBucketsMs[numLatencyBuckets-1] = -1 // +Inf sentinel
+	for i := range b.latencyBuckets {
+		s.LatencyCounts[i] = atomic.LoadInt64(&b.latencyBuckets[i])
+	}
+	return s
+}
@@ -0,0 +1,76 @@
+package backend
+
+import (
+	"encoding/json"
+	"net"
+	"net/http"
+	"time"
+)
+
+// TransportConfig tunes the *http.Transport used to dial a backend.
+type TransportConfig struct {
+	DialTimeout           time.Duration
+	TLSHandshakeTimeout   time.Duration
+	ResponseHeaderTimeout time.Duration
+	MaxIdleConnsPerHost   int
+}
+
+// DefaultTransportConfig mirrors net/http.DefaultTransport closely
+// enough to be a drop-in for the reverse proxy's previous zero-value
+// transport.
+func DefaultTransportConfig() TransportConfig {
+	return TransportConfig{
+		DialTimeout:         30 * time.Second,
+		TLSHandshakeTimeout: 10 * time.Second,
+		MaxIdleConnsPerHost: 2,
+	}
+}
+
+// WithDefaults fills in any zero-valued field of c from
+// DefaultTransportConfig.
+func (c TransportConfig) WithDefaults() TransportConfig {
+	d := DefaultTransportConfig()
+	if c.DialTimeout <= 0 {
+		c.DialTimeout = d.DialTimeout
+	}
+	if c.TLSHandshakeTimeout <= 0 {
+		c.TLSHandshakeTimeout = d.TLSHandshakeTimeout
+	}
+	if c.MaxIdleConnsPerHost <= 0 {
+		c.MaxIdleConnsPerHost = d.MaxIdleConnsPerHost
+	}
+	return c
+}
+
+// UnmarshalJSON accepts the timeout fields as plain seconds, matching
+// the rest of this project's config.json.
+func (c *TransportConfig) UnmarshalJSON(data []byte) error {
+	var raw struct {
+		DialTimeout           float64 `json:"dial_timeout"`
+		TLSHandshakeTimeout   float64 `json:"tls_handshake_timeout"`
+		ResponseHeaderTimeout float64 `json:"response_header_timeout"`
+		MaxIdleConnsPerHost   int     `json:"max_idle_conns_per_host"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+
+	*c = TransportConfig{
+		DialTimeout:           time.Duration(raw.DialTimeout * float64(time.Second)),
+		TLSHandshakeTimeout:   time.Duration(raw.TLSHandshakeTimeout * float64(time.Second)),
+		ResponseHeaderTimeout: time.Duration(raw.ResponseHeaderTimeout * float64(time.Second)),
+		MaxIdleConnsPerHost:   raw.MaxIdleConnsPerHost,
+	}
+	return nil
+}
+
+// NewTransport builds an *http.Transport from cfg.
+func NewTransport(cfg TransportConfig) *http.Transport {
+	dialer := &net.Dialer{Timeout: cfg.DialTimeout}
+	return &http.Transport{
+		DialContext:           dialer.DialContext,
+		TLSHandshakeTimeout:   cfg.TLSHandshakeTimeout,
+		ResponseHeaderTimeout: cfg.ResponseHeaderTimeout,
+		MaxIdleConnsPerHost:   cfg.MaxIdleConnsPerHost,
+	}
+}
@@ -0,0 +1,136 @@
+package fastcgi
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/textproto"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// requestID is fixed at 1: each request dials its own connection, so
+// there's never more than one in-flight FastCGI request to multiplex.
+const requestID = 1
+
+// Handler proxies HTTP requests to a FastCGI application server (e.g.
+// PHP-FPM), such as would be configured as an Upstream on a
+// backend.Backend alongside the HTTP and gRPC ones.
+type Handler struct {
+	// Network and Addr identify the FastCGI server, e.g. ("tcp",
+	// "127.0.0.1:9000") or ("unix", "/var/run/php-fpm.sock").
+	Network string
+	Addr    string
+	// Root is the document root FastCGI scripts are resolved under; it
+	// becomes SCRIPT_FILENAME's and DOCUMENT_ROOT's base directory.
+	Root string
+	// DialTimeout bounds connecting to the FastCGI server. Zero means no
+	// timeout.
+	DialTimeout time.Duration
+}
+
+// NewHandler builds a Handler dialing (network, addr) for every request,
+// resolving scripts under root.
+func NewHandler(network, addr, root string) *Handler {
+	return &Handler{Network: network, Addr: addr, Root: root}
+}
+
+// ServeHTTP implements http.Handler by opening a new FastCGI connection,
+// sending the request as BeginRequest/Params/Stdin records, and
+// translating the Stdout records back into an HTTP response.
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	conn, err := net.DialTimeout(h.Network, h.Addr, h.DialTimeout)
+	if err != nil {
+		http.Error(w, "fastcgi: "+err.Error(), http.StatusBadGateway)
+		return
+	}
+	defer conn.Close()
+
+	if err := writeBeginRequest(conn, requestID); err != nil {
+		http.Error(w, "fastcgi: "+err.Error(), http.StatusBadGateway)
+		return
+	}
+	if err := writeParams(conn, requestID, h.params(r)); err != nil {
+		http.Error(w, "fastcgi: "+err.Error(), http.StatusBadGateway)
+		return
+	}
+	if err := writeStdin(conn, requestID, r.Body); err != nil {
+		http.Error(w, "fastcgi: "+err.Error(), http.StatusBadGateway)
+		return
+	}
+
+	resp, err := readResponse(bufio.NewReader(conn), requestID)
+	if err != nil {
+		http.Error(w, "fastcgi: "+err.Error(), http.StatusBadGateway)
+		return
+	}
+	if resp.protoStatus != 0 {
+		http.Error(w, fmt.Sprintf("fastcgi: protocol status %d", resp.protoStatus), http.StatusBadGateway)
+		return
+	}
+
+	writeStdoutResponse(w, resp)
+}
+
+// params maps r onto the CGI/1.1 parameters PHP-FPM and friends expect.
+func (h *Handler) params(r *http.Request) map[string]string {
+	scriptName := r.URL.Path
+	scriptFilename := path.Join(h.Root, scriptName)
+
+	params := map[string]string{
+		"GATEWAY_INTERFACE": "CGI/1.1",
+		"SERVER_PROTOCOL":   r.Proto,
+		"SERVER_SOFTWARE":   "LB",
+		"REQUEST_METHOD":    r.Method,
+		"REQUEST_URI":       r.URL.RequestURI(),
+		"SCRIPT_NAME":       scriptName,
+		"SCRIPT_FILENAME":   scriptFilename,
+		"DOCUMENT_ROOT":     h.Root,
+		"PATH_INFO":         scriptName,
+		"QUERY_STRING":      r.URL.RawQuery,
+		"CONTENT_TYPE":      r.Header.Get("Content-Type"),
+		"CONTENT_LENGTH":    strconv.FormatInt(r.ContentLength, 10),
+		"REMOTE_ADDR":       r.RemoteAddr,
+		"SERVER_NAME":       r.Host,
+	}
+	for name, values := range r.Header {
+		key := "HTTP_" + strings.ToUpper(strings.ReplaceAll(name, "-", "_"))
+		params[key] = strings.Join(values, ", ")
+	}
+	return params
+}
+
+// writeStdoutResponse parses the CGI response header block (a
+// status/header section, a blank line, then the body) out of resp's
+// stdout stream and writes it to w.
+func writeStdoutResponse(w http.ResponseWriter, resp *response) {
+	raw := resp.stdout.Bytes()
+	tp := textproto.NewReader(bufio.NewReader(&resp.stdout))
+	mimeHeader, err := tp.ReadMIMEHeader()
+	if err != nil && len(mimeHeader) == 0 {
+		// No CGI header block at all: treat the whole stdout as the body.
+		w.Write(raw)
+		return
+	}
+
+	status := http.StatusOK
+	if s := mimeHeader.Get("Status"); s != "" {
+		if code, convErr := strconv.Atoi(strings.Fields(s)[0]); convErr == nil {
+			status = code
+		}
+		mimeHeader.Del("Status")
+	}
+
+	dst := w.Header()
+	for k, vs := range mimeHeader {
+		for _, v := range vs {
+			dst.Add(k, v)
+		}
+	}
+	w.WriteHeader(status)
+	io.Copy(w, tp.R)
+}
@@ -0,0 +1,186 @@
+// Package fastcgi implements a minimal FastCGI client transport so the
+// load balancer can proxy to PHP-FPM and similar FastCGI application
+// servers alongside its HTTP and gRPC upstreams.
+package fastcgi
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+)
+
+const (
+	version1 = 1
+
+	typeBeginRequest = 1
+	typeAbortRequest = 2
+	typeEndRequest   = 3
+	typeParams       = 4
+	typeStdin        = 5
+	typeStdout       = 6
+	typeStderr       = 7
+
+	roleResponder = 1
+
+	maxRecordContentLength = 65535
+)
+
+// header is the 8-byte FastCGI record header.
+type header struct {
+	Version       uint8
+	Type          uint8
+	RequestID     uint16
+	ContentLength uint16
+	PaddingLength uint8
+	Reserved      uint8
+}
+
+func (h *header) read(r io.Reader) error {
+	return binary.Read(r, binary.BigEndian, h)
+}
+
+func (h *header) write(w io.Writer) error {
+	return binary.Write(w, binary.BigEndian, h)
+}
+
+// writeRecord writes content as one or more FastCGI records of the given
+// type, splitting it into maxRecordContentLength chunks, and pads each
+// record's content to a multiple of 8 bytes as recommended by the spec.
+// A nil/empty content still writes a single zero-length record, which is
+// how FCGI_PARAMS and FCGI_STDIN streams are terminated.
+func writeRecord(w io.Writer, recType uint8, requestID uint16, content []byte) error {
+	for {
+		chunk := content
+		if len(chunk) > maxRecordContentLength {
+			chunk = chunk[:maxRecordContentLength]
+		}
+		content = content[len(chunk):]
+
+		padding := (8 - len(chunk)%8) % 8
+		h := header{
+			Version:       version1,
+			Type:          recType,
+			RequestID:     requestID,
+			ContentLength: uint16(len(chunk)),
+			PaddingLength: uint8(padding),
+		}
+		if err := h.write(w); err != nil {
+			return err
+		}
+		if _, err := w.Write(chunk); err != nil {
+			return err
+		}
+		if padding > 0 {
+			if _, err := w.Write(make([]byte, padding)); err != nil {
+				return err
+			}
+		}
+		if len(content) == 0 {
+			return nil
+		}
+	}
+}
+
+// writeBeginRequest writes the BEGIN_REQUEST record that opens a
+// FastCGI request, with the responder role and KeepConn unset so the
+// application server closes the connection once it's done.
+func writeBeginRequest(w io.Writer, requestID uint16) error {
+	body := make([]byte, 8)
+	binary.BigEndian.PutUint16(body[0:2], roleResponder)
+	return writeRecord(w, typeBeginRequest, requestID, body)
+}
+
+// writeParams encodes params as FCGI_PARAMS records, terminated by an
+// empty record as the protocol requires.
+func writeParams(w io.Writer, requestID uint16, params map[string]string) error {
+	var buf bytes.Buffer
+	for k, v := range params {
+		writeParamLength(&buf, len(k))
+		writeParamLength(&buf, len(v))
+		buf.WriteString(k)
+		buf.WriteString(v)
+	}
+	if err := writeRecord(w, typeParams, requestID, buf.Bytes()); err != nil {
+		return err
+	}
+	return writeRecord(w, typeParams, requestID, nil)
+}
+
+func writeParamLength(buf *bytes.Buffer, n int) {
+	if n <= 127 {
+		buf.WriteByte(byte(n))
+		return
+	}
+	var b [4]byte
+	binary.BigEndian.PutUint32(b[:], uint32(n)|1<<31)
+	buf.Write(b[:])
+}
+
+// writeStdin streams body as FCGI_STDIN records, terminated by an empty
+// record, honoring maxRecordContentLength per record.
+func writeStdin(w io.Writer, requestID uint16, body io.Reader) error {
+	buf := make([]byte, maxRecordContentLength)
+	for {
+		n, err := body.Read(buf)
+		if n > 0 {
+			if werr := writeRecord(w, typeStdin, requestID, buf[:n]); werr != nil {
+				return werr
+			}
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return writeRecord(w, typeStdin, requestID, nil)
+}
+
+// response accumulates the stdout/stderr/end-request records read back
+// from the application server for a single request.
+type response struct {
+	stdout      bytes.Buffer
+	stderr      bytes.Buffer
+	appStatus   int32
+	protoStatus uint8
+}
+
+// readResponse reads records from r until the matching EndRequest record
+// arrives, demultiplexing stdout/stderr by record type.
+func readResponse(r io.Reader, requestID uint16) (*response, error) {
+	resp := &response{}
+	for {
+		var h header
+		if err := h.read(r); err != nil {
+			return nil, err
+		}
+		content := make([]byte, h.ContentLength)
+		if _, err := io.ReadFull(r, content); err != nil {
+			return nil, err
+		}
+		if h.PaddingLength > 0 {
+			if _, err := io.CopyN(io.Discard, r, int64(h.PaddingLength)); err != nil {
+				return nil, err
+			}
+		}
+		if h.RequestID != requestID {
+			continue
+		}
+
+		switch h.Type {
+		case typeStdout:
+			resp.stdout.Write(content)
+		case typeStderr:
+			resp.stderr.Write(content)
+		case typeEndRequest:
+			if len(content) < 8 {
+				return nil, errors.New("fastcgi: short EndRequest record")
+			}
+			resp.appStatus = int32(binary.BigEndian.Uint32(content[0:4]))
+			resp.protoStatus = content[4]
+			return resp, nil
+		}
+	}
+}
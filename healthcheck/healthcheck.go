@@ -0,0 +1,154 @@
+// Package healthcheck implements active backend probing for the load
+// balancer: periodic checks that decide whether a backend is reachable,
+// independent of the passive circuit breaker in the backend package that
+// reacts to proxied-request outcomes.
+package healthcheck
+
+import (
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Mode selects how a backend is actively probed.
+type Mode string
+
+const (
+	// ModeTCP only opens and closes a TCP connection.
+	ModeTCP Mode = "tcp"
+	// ModeHTTP issues an HTTP GET and checks the status code and, optionally,
+	// a response body substring.
+	ModeHTTP Mode = "http"
+)
+
+// Config describes how a backend should be actively probed.
+type Config struct {
+	Mode               Mode
+	Path               string
+	ExpectStatus       int
+	ExpectBody         string
+	Interval           time.Duration
+	Timeout            time.Duration
+	UnhealthyThreshold int
+	HealthyThreshold   int
+}
+
+// DefaultConfig is a plain TCP-connect probe every 10s with a 2s timeout.
+// Its thresholds size each backend's passive circuit breaker, which opens
+// after 3 failed requests within one Interval and closes again after 1
+// successful half-open request. Active probing does not use the
+// thresholds: each probe's result sets the backend's alive flag directly.
+func DefaultConfig() Config {
+	return Config{
+		Mode:               ModeTCP,
+		Interval:           10 * time.Second,
+		Timeout:            2 * time.Second,
+		UnhealthyThreshold: 3,
+		HealthyThreshold:   1,
+	}
+}
+
+// WithDefaults fills in any zero-valued field of c from DefaultConfig.
+func (c Config) WithDefaults() Config {
+	d := DefaultConfig()
+	if c.Mode == "" {
+		c.Mode = d.Mode
+	}
+	if c.Interval <= 0 {
+		c.Interval = d.Interval
+	}
+	if c.Timeout <= 0 {
+		c.Timeout = d.Timeout
+	}
+	if c.UnhealthyThreshold <= 0 {
+		c.UnhealthyThreshold = d.UnhealthyThreshold
+	}
+	if c.HealthyThreshold <= 0 {
+		c.HealthyThreshold = d.HealthyThreshold
+	}
+	return c
+}
+
+// UnmarshalJSON accepts interval/timeout as plain seconds, matching the
+// rest of this project's config.json, rather than requiring Go duration
+// strings.
+func (c *Config) UnmarshalJSON(data []byte) error {
+	var raw struct {
+		Mode               Mode    `json:"mode"`
+		Path               string  `json:"path"`
+		ExpectStatus       int     `json:"expect_status"`
+		ExpectBody         string  `json:"expect_body"`
+		Interval           float64 `json:"interval"`
+		Timeout            float64 `json:"timeout"`
+		UnhealthyThreshold int     `json:"unhealthy_threshold"`
+		HealthyThreshold   int     `json:"healthy_threshold"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+
+	*c = Config{
+		Mode:               raw.Mode,
+		Path:               raw.Path,
+		ExpectStatus:       raw.ExpectStatus,
+		ExpectBody:         raw.ExpectBody,
+		Interval:           time.Duration(raw.Interval * float64(time.Second)),
+		Timeout:            time.Duration(raw.Timeout * float64(time.Second)),
+		UnhealthyThreshold: raw.UnhealthyThreshold,
+		HealthyThreshold:   raw.HealthyThreshold,
+	}
+	return nil
+}
+
+// Probe runs a single active health check against u and reports whether
+// the backend appears healthy.
+func Probe(u *url.URL, cfg Config) bool {
+	if cfg.Mode == ModeHTTP {
+		return probeHTTP(u, cfg)
+	}
+	return probeTCP(u, cfg)
+}
+
+func probeTCP(u *url.URL, cfg Config) bool {
+	conn, err := net.DialTimeout("tcp", u.Host, cfg.Timeout)
+	if err != nil {
+		return false
+	}
+	_ = conn.Close()
+	return true
+}
+
+func probeHTTP(u *url.URL, cfg Config) bool {
+	probeURL := *u
+	if cfg.Path != "" {
+		probeURL.Path = cfg.Path
+	}
+
+	client := http.Client{Timeout: cfg.Timeout}
+	resp, err := client.Get(probeURL.String())
+	if err != nil {
+		return false
+	}
+	defer resp.Body.Close()
+
+	expectStatus := cfg.ExpectStatus
+	if expectStatus == 0 {
+		expectStatus = http.StatusOK
+	}
+	if resp.StatusCode != expectStatus {
+		return false
+	}
+
+	if cfg.ExpectBody == "" {
+		return true
+	}
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return false
+	}
+	return strings.Contains(string(body), cfg.ExpectBody)
+}
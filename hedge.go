@@ -0,0 +1,113 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/harshit0410/LB/backend"
+)
+
+// bufferedResponse captures a handler's response in memory so a hedge
+// race can pick a winner before anything is written to the real client.
+type bufferedResponse struct {
+	header http.Header
+	status int
+	body   bytes.Buffer
+}
+
+func newBufferedResponse() *bufferedResponse {
+	return &bufferedResponse{header: make(http.Header)}
+}
+
+func (b *bufferedResponse) Header() http.Header { return b.header }
+
+func (b *bufferedResponse) Write(p []byte) (int, error) { return b.body.Write(p) }
+
+func (b *bufferedResponse) WriteHeader(status int) { b.status = status }
+
+func (b *bufferedResponse) flushTo(w http.ResponseWriter) {
+	dst := w.Header()
+	for k, vs := range b.header {
+		for _, v := range vs {
+			dst.Add(k, v)
+		}
+	}
+	if b.status == 0 {
+		b.status = http.StatusOK
+	}
+	w.WriteHeader(b.status)
+	w.Write(b.body.Bytes())
+}
+
+// bufferRequestBody reads req's body into memory and replaces it with a
+// no-op-closing reader over the buffered bytes, returning those bytes so
+// serveHedged can give each leg of the race its own independent copy
+// instead of both legs fighting over the one body reader.
+func bufferRequestBody(req *http.Request) ([]byte, error) {
+	if req.Body == nil || req.Body == http.NoBody {
+		return nil, nil
+	}
+	body, err := io.ReadAll(req.Body)
+	req.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	req.Body = io.NopCloser(bytes.NewReader(body))
+	return body, nil
+}
+
+// serveHedged races req against primary and, if primary hasn't responded
+// within delay, also against secondary, writing whichever response comes
+// back first and cancelling the loser. body is req's already-buffered
+// request body (see bufferRequestBody), cloned for each leg so the
+// secondary doesn't replay whatever the primary has already drained.
+func serveHedged(w http.ResponseWriter, req *http.Request, primary, secondary *backend.Backend, delay time.Duration, body []byte) {
+	type race struct {
+		resp *bufferedResponse
+	}
+	results := make(chan race, 2)
+
+	fire := func(peer *backend.Backend, ctx context.Context) {
+		peer.IncConnections()
+		defer peer.DecConnections()
+		legReq := req.WithContext(ctx)
+		if body != nil {
+			legReq.Body = io.NopCloser(bytes.NewReader(body))
+		}
+		resp := newBufferedResponse()
+		peer.Upstream.ServeHTTP(resp, legReq)
+		select {
+		case results <- race{resp}:
+		default:
+		}
+	}
+
+	primaryCtx, cancelPrimary := context.WithCancel(req.Context())
+	defer cancelPrimary()
+	secondaryCtx, cancelSecondary := context.WithCancel(req.Context())
+	defer cancelSecondary()
+
+	go fire(primary, primaryCtx)
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	select {
+	case winner := <-results:
+		cancelSecondary()
+		winner.resp.flushTo(w)
+		return
+	case <-timer.C:
+	case <-req.Context().Done():
+		return
+	}
+
+	go fire(secondary, secondaryCtx)
+	winner := <-results
+	cancelPrimary()
+	cancelSecondary()
+	winner.resp.flushTo(w)
+}
@@ -3,6 +3,7 @@ package main
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io/ioutil"
 	"log"
@@ -12,20 +13,60 @@ import (
 	"os"
 	"os/signal"
 	"sync"
+	"sync/atomic"
 	"syscall"
 	"time"
 
 	"github.com/gorilla/mux"
 	"github.com/harshit0410/LB/backend"
+	"github.com/harshit0410/LB/fastcgi"
+	"github.com/harshit0410/LB/healthcheck"
+	"github.com/harshit0410/LB/retry"
 	"github.com/harshit0410/LB/serverpool"
 )
 
 const (
 	Attempts int = iota
 	Retry
+	requestStart
 )
 
-var serverPool serverpool.ServerPool
+var serverPool *serverpool.ServerPool
+
+// retryPolicyValue and transportCfgValue are read by every backend's
+// reverse proxy hooks and written by the admin goroutines handling a
+// config reload, from different goroutines than the ones serving
+// requests; atomic.Pointer makes that safe without a lock. Use
+// getRetryPolicy/setRetryPolicy and getTransportConfig/setTransportConfig
+// rather than touching these directly.
+var retryPolicyValue atomic.Pointer[retry.Policy]
+var transportCfgValue atomic.Pointer[backend.TransportConfig]
+
+func init() {
+	setRetryPolicy(retry.DefaultPolicy())
+	setTransportConfig(backend.DefaultTransportConfig())
+}
+
+func getRetryPolicy() retry.Policy {
+	return *retryPolicyValue.Load()
+}
+
+func setRetryPolicy(p retry.Policy) {
+	retryPolicyValue.Store(&p)
+}
+
+func getTransportConfig() backend.TransportConfig {
+	return *transportCfgValue.Load()
+}
+
+func setTransportConfig(c backend.TransportConfig) {
+	transportCfgValue.Store(&c)
+}
+
+// errRetryableStatus is returned from a backend's ModifyResponse hook to
+// force its ErrorHandler to run when the response status is in
+// the retry policy's RetryStatusCodes.
+var errRetryableStatus = errors.New("retry: response status is retryable")
 
 func GetAttemptsFromContext(r *http.Request) int {
 	if attempts, ok := r.Context().Value(Attempts).(int); ok {
@@ -41,9 +82,9 @@ func GetRetryFromContext(r *http.Request) int {
 	return 0
 }
 
-func HealthCheck(wg sync.WaitGroup, ctx context.Context) {
+func HealthCheck(wg sync.WaitGroup, ctx context.Context, interval time.Duration) {
 	defer wg.Done()
-	t := time.NewTicker(time.Second * 10)
+	t := time.NewTicker(interval)
 	for {
 		select {
 		case <-t.C:
@@ -67,84 +108,298 @@ func LB(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	peer := serverPool.GetNextPeer()
-	if peer != nil {
-		peer.ReverseProxy.ServeHTTP(w, r)
+	peer := serverPool.Select(r)
+	if peer == nil {
+		http.Error(w, "Service not available", http.StatusServiceUnavailable)
 		return
 	}
-	http.Error(w, "Service not available", http.StatusServiceUnavailable)
+
+	ctx := context.WithValue(r.Context(), requestStart, time.Now())
+	req := r.WithContext(ctx)
+
+	policy := getRetryPolicy()
+	if policy.HedgeDelay > 0 && policy.AllowMethod(req) {
+		if second := serverPool.Select(req); second != nil && second != peer {
+			body, err := bufferRequestBody(req)
+			if err != nil {
+				http.Error(w, "Service not available", http.StatusServiceUnavailable)
+				return
+			}
+			serveHedged(w, req, peer, second, policy.HedgeDelay, body)
+			return
+		}
+	}
+
+	peer.IncConnections()
+	defer peer.DecConnections()
+	peer.Upstream.ServeHTTP(w, req)
 }
 
-func addServerToPool(serverList []string) {
-	for _, tok := range serverList {
-		serverUrl, err := url.Parse(tok)
-		if err != nil {
-			log.Fatal(err)
+func latencySince(ctx context.Context) time.Duration {
+	if start, ok := ctx.Value(requestStart).(time.Time); ok {
+		return time.Since(start)
+	}
+	return 0
+}
+
+// UpstreamConfig selects a backend's wire protocol and any
+// protocol-specific options. It's keyed by URL in Config.Upstreams, the
+// same way Config.Weights is, so a backend with no entry just gets
+// ProtoHTTP with no extra options.
+type UpstreamConfig struct {
+	// Proto is "http" (the default), "fastcgi", or "grpc".
+	Proto string `json:"proto"`
+	// Root is the document root FastCGI scripts are resolved under.
+	// Ignored for every other proto.
+	Root string `json:"root"`
+}
+
+const (
+	ProtoHTTP    = "http"
+	ProtoFastCGI = "fastcgi"
+	ProtoGRPC    = "grpc"
+)
+
+// newReverseProxy builds the *httputil.ReverseProxy shared by the http
+// and grpc protos: both differ only in the transport they dial with, and
+// get the same retry/circuit-breaker/stats wiring around it.
+func newReverseProxy(b *backend.Backend, transport http.RoundTripper) *httputil.ReverseProxy {
+	serverUrl := b.URL
+	proxy := httputil.NewSingleHostReverseProxy(serverUrl)
+	proxy.Transport = transport
+	proxy.ModifyResponse = func(resp *http.Response) error {
+		policy := getRetryPolicy()
+		if policy.AllowStatus(resp.StatusCode) && policy.AllowMethod(resp.Request) {
+			return errRetryableStatus
+		}
+		b.Breaker.RecordSuccess()
+		b.RecordSuccess(latencySince(resp.Request.Context()))
+		return nil
+	}
+	proxy.ErrorHandler = func(writer http.ResponseWriter, request *http.Request, e error) {
+		log.Printf("[%s] %s\n", serverUrl.Host, e.Error())
+		b.Breaker.RecordFailure()
+		b.RecordError(latencySince(request.Context()))
+
+		if request.Context().Err() != nil {
+			// Client disconnected, or a hedge race already has a winner:
+			// don't keep retrying a request nobody is waiting on.
+			return
 		}
 
-		proxy := httputil.NewSingleHostReverseProxy(serverUrl)
-		proxy.ErrorHandler = func(writer http.ResponseWriter, request *http.Request, e error) {
-			log.Printf("[%s] %s\n", serverUrl.Host, e.Error())
-			retries := GetRetryFromContext(request)
-			if retries < 3 {
-				select {
-				case <-time.After(10 * time.Millisecond):
-					ctx := context.WithValue(request.Context(), Retry, retries+1)
-					proxy.ServeHTTP(writer, request.WithContext(ctx))
-				}
-				return
+		policy := getRetryPolicy().WithDefaults()
+		retries := GetRetryFromContext(request)
+		withinBudget := policy.MaxElapsed <= 0 || latencySince(request.Context()) < policy.MaxElapsed
+		if retries < policy.MaxRetries && policy.AllowMethod(request) && withinBudget {
+			timer := time.NewTimer(policy.Backoff(retries + 1))
+			defer timer.Stop()
+			select {
+			case <-timer.C:
+				ctx := context.WithValue(request.Context(), Retry, retries+1)
+				proxy.ServeHTTP(writer, request.WithContext(ctx))
+			case <-request.Context().Done():
 			}
+			return
+		}
 
-			// after 3 retries, mark this backend as down
-			serverPool.MarkBackendStatus(serverUrl, false)
+		// retries exhausted (or this request/status isn't retryable here):
+		// mark this backend as down and let the load balancer try another.
+		b.SetAlive(false)
 
-			// if the same request routing for few attempts with different backends, increase the count
-			attempts := GetAttemptsFromContext(request)
-			log.Printf("%s(%s) Attempting retry %d\n", request.RemoteAddr, request.URL.Path, attempts)
-			ctx := context.WithValue(request.Context(), Attempts, attempts+1)
-			LB(writer, request.WithContext(ctx))
+		// if the same request routing for few attempts with different backends, increase the count
+		attempts := GetAttemptsFromContext(request)
+		log.Printf("%s(%s) Attempting retry %d\n", request.RemoteAddr, request.URL.Path, attempts)
+		ctx := context.WithValue(request.Context(), Attempts, attempts+1)
+		LB(writer, request.WithContext(ctx))
+	}
+	return proxy
+}
+
+// fastcgiAddr splits a "unix:/path" or "tcp host:port"-shaped backend URL
+// into the (network, addr) pair net.Dial expects.
+func fastcgiAddr(u *url.URL) (network, addr string) {
+	if u.Scheme == "unix" {
+		if u.Opaque != "" {
+			return "unix", u.Opaque
 		}
+		return "unix", u.Path
+	}
+	return "tcp", u.Host
+}
+
+// healthOverrideFor returns overrides[tok] as a *healthcheck.Config, or
+// nil if tok has no entry, so the backend falls back to inheriting the
+// pool's health-check config.
+func healthOverrideFor(tok string, overrides map[string]healthcheck.Config) *healthcheck.Config {
+	cfg, ok := overrides[tok]
+	if !ok {
+		return nil
+	}
+	return &cfg
+}
+
+// newBackend builds a Backend for tok under the given upstream proto,
+// wiring its transport and (for http/grpc) retry, circuit-breaker, and
+// stats hooks. health, if non-nil, overrides the pool's health-check
+// config for this backend alone. It does not register the backend with
+// serverPool.
+func newBackend(tok string, weight int, up UpstreamConfig, health *healthcheck.Config) (*backend.Backend, error) {
+	serverUrl, err := url.Parse(tok)
+	if err != nil {
+		return nil, err
+	}
+
+	b := &backend.Backend{
+		URL:    serverUrl,
+		Alive:  true,
+		Weight: weight,
+	}
+	if health != nil {
+		b.SetHealthConfig(health)
+	}
+
+	switch up.Proto {
+	case "", ProtoHTTP:
+		b.Upstream = newReverseProxy(b, backend.NewTransport(getTransportConfig()))
+	case ProtoGRPC:
+		b.Upstream = newReverseProxy(b, backend.NewH2CTransport(getTransportConfig()))
+	case ProtoFastCGI:
+		network, addr := fastcgiAddr(serverUrl)
+		fc := fastcgi.NewHandler(network, addr, up.Root)
+		fc.DialTimeout = getTransportConfig().WithDefaults().DialTimeout
+		b.Upstream = &statsUpstream{backend: b, handler: fc}
+	default:
+		return nil, fmt.Errorf("newBackend: unknown upstream proto %q", up.Proto)
+	}
+
+	return b, nil
+}
+
+// statsUpstream wraps an Upstream that isn't an *httputil.ReverseProxy
+// (and so doesn't get ModifyResponse/ErrorHandler hooks of its own) so it
+// still reports outcomes into the backend's circuit breaker and stats.
+// It doesn't retry failed requests the way the HTTP/gRPC path does,
+// since that path's retries lean on transport-level error semantics this
+// wrapper's handler doesn't expose.
+type statsUpstream struct {
+	backend *backend.Backend
+	handler http.Handler
+}
+
+func (s *statsUpstream) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+	s.handler.ServeHTTP(rec, r)
+
+	latency := latencySince(r.Context())
+	if rec.status >= http.StatusInternalServerError {
+		s.backend.Breaker.RecordFailure()
+		s.backend.RecordError(latency)
+		return
+	}
+	s.backend.Breaker.RecordSuccess()
+	s.backend.RecordSuccess(latency)
+}
+
+// statusRecorder remembers the status code an http.Handler wrote, since
+// http.ResponseWriter itself doesn't expose it.
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (r *statusRecorder) WriteHeader(status int) {
+	r.status = status
+	r.ResponseWriter.WriteHeader(status)
+}
 
-		serverPool.AddBackend(&backend.Backend{
-			URL:          serverUrl,
-			Alive:        true,
-			ReverseProxy: proxy,
-		})
-		log.Printf("Configured server: %s\n", serverUrl)
+func addServerToPool(serverList []string, weights map[string]int, upstreams map[string]UpstreamConfig, healthOverrides map[string]healthcheck.Config) {
+	for _, tok := range serverList {
+		b, err := newBackend(tok, weights[tok], upstreams[tok], healthOverrideFor(tok, healthOverrides))
+		if err != nil {
+			log.Fatal(err)
+		}
+		serverPool.AddBackend(b)
+		log.Printf("Configured server: %s\n", b.URL)
 	}
 }
 
 type Config struct {
-	Port string   `json:"port" binding:"required"`
-	Urls []string `json:"urls" binding:"required"`
+	Port      string                    `json:"port" binding:"required"`
+	Urls      []string                  `json:"urls" binding:"required"`
+	Policy    string                    `json:"policy"`
+	Weights   map[string]int            `json:"weights"`
+	Upstreams map[string]UpstreamConfig `json:"upstreams"`
+	// Health is the pool-wide default active health-check config.
+	// HealthOverrides, keyed by URL the same way Weights/Upstreams are,
+	// lets individual backends override it (e.g. a slower-to-start
+	// backend that needs a longer interval/timeout).
+	Health          healthcheck.Config            `json:"health"`
+	HealthOverrides map[string]healthcheck.Config `json:"health_overrides"`
+	Admin           AdminAuth                     `json:"admin"`
+	Retry           retry.Policy                  `json:"retry"`
+	Transport       backend.TransportConfig       `json:"transport"`
 }
 
-var cfg Config
+// AdminAuth protects the admin API. If Token is set, requests must carry
+// "Authorization: Bearer <token>". Otherwise, if Username is set,
+// requests must pass matching HTTP basic auth. If neither is set, the
+// admin API is unprotected.
+type AdminAuth struct {
+	Token    string `json:"token"`
+	Username string `json:"username"`
+	Password string `json:"password"`
+}
+
+// cfgValue holds the active Config. It's written by persistConfig on an
+// admin goroutine (a config reload) and read by requireAdminAuth and the
+// admin API's GET handlers on other admin goroutines; atomic.Pointer
+// makes that safe, the same way retryPolicyValue/transportCfgValue do
+// for the retry policy and transport config.
+var cfgValue atomic.Pointer[Config]
+
+func getConfig() Config {
+	return *cfgValue.Load()
+}
+
+func setConfig(c Config) {
+	cfgValue.Store(&c)
+}
 
 func main() {
 	data, err := ioutil.ReadFile("./config.json")
 	if err != nil {
 		log.Fatal(err.Error())
 	}
-	json.Unmarshal(data, &cfg)
+	var c Config
+	json.Unmarshal(data, &c)
 
-	if len(cfg.Urls) == 0 {
+	if len(c.Urls) == 0 {
 		log.Fatal("Please provide one or more backends to load balance")
 	}
 
-	addServerToPool(cfg.Urls)
+	serverPool, err = serverpool.NewServerPool(c.Policy)
+	if err != nil {
+		log.Fatal(err)
+	}
+	serverPool.SetHealthCheckConfig(c.Health)
+	setRetryPolicy(c.Retry.WithDefaults())
+	setTransportConfig(c.Transport.WithDefaults())
+
+	addServerToPool(c.Urls, c.Weights, c.Upstreams, c.HealthOverrides)
 
-	if cfg.Port == "" {
-		cfg.Port = "3000"
+	if c.Port == "" {
+		c.Port = "3000"
 	}
+	setConfig(c)
 
 	server := &http.Server{
-		Addr:    fmt.Sprintf(":%s", cfg.Port),
+		Addr:    fmt.Sprintf(":%s", c.Port),
 		Handler: http.HandlerFunc(LB),
 	}
 
 	router := mux.NewRouter().StrictSlash(true)
-	router.HandleFunc("/config", UpdateConfig).Methods("PUT")
+	router.HandleFunc("/config", requireAdminAuth(UpdateConfig)).Methods("PUT")
+	registerAdminRoutes(router)
 	server2 := &http.Server{
 		Addr:    ":3000",
 		Handler: router,
@@ -156,7 +411,7 @@ func main() {
 	wg.Add(3)
 
 	// start health checking
-	go HealthCheck(wg, ctx)
+	go HealthCheck(wg, ctx, c.Health.WithDefaults().Interval)
 
 	go startServer(wg, server)
 	go startServer(wg, server2)
@@ -202,20 +457,64 @@ func UpdateConfig(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	content, err := json.Marshal(inputConfig)
+	persistConfig(inputConfig)
+	applyConfigDiff(inputConfig)
+
+	w.Header().Set("content-type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(inputConfig)
+}
+
+// persistConfig writes c to config.json and makes it the process's
+// active Config, so GET /admin/config and a future restart agree with it.
+func persistConfig(c Config) {
+	content, err := json.Marshal(c)
 	if err != nil {
 		fmt.Println(err)
+		return
 	}
-
-	err = ioutil.WriteFile("config.json", content, 0644)
-	if err != nil {
+	if err := ioutil.WriteFile("config.json", content, 0644); err != nil {
 		fmt.Println(err)
 	}
+	setConfig(c)
+}
 
-	serverPool.RemoveAllBackend()
-	addServerToPool(inputConfig.Urls)
+// applyConfigDiff reconciles the live backend set against desired,
+// adding and removing backends as needed and updating weights in place,
+// rather than tearing the whole pool down the way a RemoveAllBackend +
+// addServerToPool rebuild would.
+func applyConfigDiff(desired Config) {
+	serverPool.SetHealthCheckConfig(desired.Health)
+	setRetryPolicy(desired.Retry.WithDefaults())
+	setTransportConfig(desired.Transport.WithDefaults())
+
+	wanted := make(map[string]bool, len(desired.Urls))
+	for _, u := range desired.Urls {
+		wanted[u] = true
+	}
 
-	w.Header().Set("content-type", "application/json")
-	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode(inputConfig)
+	var toRemove []string
+	for _, b := range serverPool.Backends() {
+		if !wanted[b.URL.String()] {
+			toRemove = append(toRemove, b.URL.String())
+		}
+	}
+	for _, u := range toRemove {
+		serverPool.RemoveBackend(u)
+	}
+
+	for _, u := range desired.Urls {
+		if b := serverPool.FindBackend(u); b != nil {
+			b.SetWeight(desired.Weights[u])
+			b.SetHealthConfig(healthOverrideFor(u, desired.HealthOverrides))
+			continue
+		}
+		b, err := newBackend(u, desired.Weights[u], desired.Upstreams[u], healthOverrideFor(u, desired.HealthOverrides))
+		if err != nil {
+			log.Printf("skipping backend %q: %v\n", u, err)
+			continue
+		}
+		serverPool.AddBackend(b)
+		log.Printf("Configured server: %s\n", b.URL)
+	}
 }
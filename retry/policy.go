@@ -0,0 +1,137 @@
+// Package retry configures how the load balancer retries a proxied
+// request against the same backend, and how it optionally hedges a
+// request across two backends, before giving up on that attempt.
+package retry
+
+import (
+	"encoding/json"
+	"math"
+	"math/rand"
+	"net/http"
+	"time"
+)
+
+// Policy configures per-backend retry behavior.
+type Policy struct {
+	// MaxRetries is how many times a failed request is retried against
+	// the same backend before the caller falls back to a different one.
+	MaxRetries int
+	// BaseBackoff is the delay before the first retry; later retries back
+	// off exponentially from it, with full jitter.
+	BaseBackoff time.Duration
+	// MaxBackoff caps the backoff delay.
+	MaxBackoff time.Duration
+	// MaxElapsed bounds the total time spent retrying a single attempt;
+	// zero means unbounded.
+	MaxElapsed time.Duration
+	// RetryStatusCodes are response status codes that trigger a retry in
+	// addition to transport errors. Empty means only transport errors do.
+	RetryStatusCodes map[int]bool
+	// IdempotentMethodsOnly restricts retries (and hedging) to GET, HEAD,
+	// OPTIONS, PUT and DELETE.
+	IdempotentMethodsOnly bool
+	// HedgeDelay, if positive, fires a second request at another backend
+	// if the first hasn't responded within this delay, and returns
+	// whichever responds first. Zero disables hedging.
+	HedgeDelay time.Duration
+}
+
+// DefaultPolicy allows up to 3 retries, every method, no hedging. Since
+// MaxBackoff equals BaseBackoff, each retry waits a jittered delay of at
+// most 10ms rather than growing exponentially.
+func DefaultPolicy() Policy {
+	return Policy{
+		MaxRetries:  3,
+		BaseBackoff: 10 * time.Millisecond,
+		MaxBackoff:  10 * time.Millisecond,
+	}
+}
+
+// WithDefaults fills in BaseBackoff and MaxRetries from DefaultPolicy
+// when left unset, and an unset MaxBackoff from the resulting
+// BaseBackoff.
+func (p Policy) WithDefaults() Policy {
+	d := DefaultPolicy()
+	if p.BaseBackoff <= 0 {
+		p.BaseBackoff = d.BaseBackoff
+	}
+	if p.MaxBackoff <= 0 {
+		p.MaxBackoff = p.BaseBackoff
+	}
+	if p.MaxRetries <= 0 {
+		p.MaxRetries = d.MaxRetries
+	}
+	return p
+}
+
+// UnmarshalJSON accepts durations as plain seconds and
+// retry_status_codes as a status code list, matching the rest of this
+// project's config.json.
+func (p *Policy) UnmarshalJSON(data []byte) error {
+	var raw struct {
+		MaxRetries            int     `json:"max_retries"`
+		BaseBackoff           float64 `json:"base_backoff"`
+		MaxBackoff            float64 `json:"max_backoff"`
+		MaxElapsed            float64 `json:"max_elapsed"`
+		RetryStatusCodes      []int   `json:"retry_status_codes"`
+		IdempotentMethodsOnly bool    `json:"idempotent_methods_only"`
+		HedgeDelay            float64 `json:"hedge_delay"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+
+	codes := make(map[int]bool, len(raw.RetryStatusCodes))
+	for _, c := range raw.RetryStatusCodes {
+		codes[c] = true
+	}
+
+	*p = Policy{
+		MaxRetries:            raw.MaxRetries,
+		BaseBackoff:           time.Duration(raw.BaseBackoff * float64(time.Second)),
+		MaxBackoff:            time.Duration(raw.MaxBackoff * float64(time.Second)),
+		MaxElapsed:            time.Duration(raw.MaxElapsed * float64(time.Second)),
+		RetryStatusCodes:      codes,
+		IdempotentMethodsOnly: raw.IdempotentMethodsOnly,
+		HedgeDelay:            time.Duration(raw.HedgeDelay * float64(time.Second)),
+	}
+	return nil
+}
+
+// Backoff returns the delay before retry attempt n (1-indexed),
+// exponential from BaseBackoff with full jitter, capped at MaxBackoff.
+func (p Policy) Backoff(attempt int) time.Duration {
+	if p.BaseBackoff <= 0 {
+		return 0
+	}
+	max := p.MaxBackoff
+	if max <= 0 {
+		max = p.BaseBackoff
+	}
+
+	backoff := float64(p.BaseBackoff) * math.Pow(2, float64(attempt-1))
+	if backoff > float64(max) {
+		backoff = float64(max)
+	}
+	return time.Duration(rand.Int63n(int64(backoff) + 1))
+}
+
+// AllowMethod reports whether r's method may be retried or hedged under
+// this policy.
+func (p Policy) AllowMethod(r *http.Request) bool {
+	if !p.IdempotentMethodsOnly {
+		return true
+	}
+	switch r.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodPut, http.MethodDelete:
+		return true
+	default:
+		return false
+	}
+}
+
+// AllowStatus reports whether a response with the given status code
+// should trigger a retry.
+func (p Policy) AllowStatus(status int) bool {
+	return p.RetryStatusCodes[status]
+}
@@ -0,0 +1,168 @@
+package serverpool
+
+import (
+	"hash/fnv"
+	"math/rand"
+	"net"
+	"net/http"
+	"sync"
+	"sync/atomic"
+
+	"github.com/harshit0410/LB/backend"
+)
+
+// SelectionPolicy picks a backend to serve r out of the given, already
+// alive-filtered, set of backends. Implementations must be safe for
+// concurrent use since Select is called from every request goroutine.
+type SelectionPolicy interface {
+	Select(backends []*backend.Backend, r *http.Request) *backend.Backend
+}
+
+// RoundRobinPolicy cycles through backends in order.
+type RoundRobinPolicy struct {
+	current uint64
+}
+
+func (p *RoundRobinPolicy) Select(backends []*backend.Backend, r *http.Request) *backend.Backend {
+	if len(backends) == 0 {
+		return nil
+	}
+	idx := atomic.AddUint64(&p.current, 1) % uint64(len(backends))
+	return backends[idx]
+}
+
+// RandomPolicy picks a uniformly random backend on every request.
+type RandomPolicy struct{}
+
+func (RandomPolicy) Select(backends []*backend.Backend, r *http.Request) *backend.Backend {
+	if len(backends) == 0 {
+		return nil
+	}
+	return backends[rand.Intn(len(backends))]
+}
+
+// LeastConnPolicy picks the backend with the fewest in-flight requests.
+type LeastConnPolicy struct{}
+
+func (LeastConnPolicy) Select(backends []*backend.Backend, r *http.Request) *backend.Backend {
+	if len(backends) == 0 {
+		return nil
+	}
+	best := backends[0]
+	for _, b := range backends[1:] {
+		if b.ActiveConnections() < best.ActiveConnections() {
+			best = b
+		}
+	}
+	return best
+}
+
+// WeightedRoundRobinPolicy implements smooth weighted round robin: each
+// backend is chosen proportionally to its Weight (a Backend with no
+// weight set, i.e. 0, is treated as weight 1) while still spreading
+// consecutive picks across backends rather than bursting on one.
+type WeightedRoundRobinPolicy struct {
+	mux     sync.Mutex
+	current map[string]int
+}
+
+func NewWeightedRoundRobinPolicy() *WeightedRoundRobinPolicy {
+	return &WeightedRoundRobinPolicy{current: make(map[string]int)}
+}
+
+func (p *WeightedRoundRobinPolicy) Select(backends []*backend.Backend, r *http.Request) *backend.Backend {
+	if len(backends) == 0 {
+		return nil
+	}
+
+	p.mux.Lock()
+	defer p.mux.Unlock()
+
+	total := 0
+	var best *backend.Backend
+	bestScore := 0
+	for _, b := range backends {
+		weight := b.GetWeight()
+		if weight <= 0 {
+			weight = 1
+		}
+		key := b.URL.String()
+		p.current[key] += weight
+		total += weight
+		if best == nil || p.current[key] > bestScore {
+			best = b
+			bestScore = p.current[key]
+		}
+	}
+	p.current[best.URL.String()] -= total
+	return best
+}
+
+// IPHashPolicy routes the same client IP to the same backend as long as
+// the backend set is unchanged.
+type IPHashPolicy struct{}
+
+func (IPHashPolicy) Select(backends []*backend.Backend, r *http.Request) *backend.Backend {
+	if len(backends) == 0 {
+		return nil
+	}
+	return backends[hashKey(clientIP(r))%uint32(len(backends))]
+}
+
+// URIHashPolicy routes the same request path to the same backend as long
+// as the backend set is unchanged.
+type URIHashPolicy struct{}
+
+func (URIHashPolicy) Select(backends []*backend.Backend, r *http.Request) *backend.Backend {
+	if len(backends) == 0 {
+		return nil
+	}
+	return backends[hashKey(r.URL.Path)%uint32(len(backends))]
+}
+
+// ConsistentHashPolicy selects a backend using rendezvous (highest random
+// weight) hashing: each backend's score for a key is computed
+// independently, so when a backend leaves the pool only the keys it was
+// winning are remapped, rather than reshuffling the whole keyspace as a
+// modulo hash would.
+type ConsistentHashPolicy struct{}
+
+func (ConsistentHashPolicy) Select(backends []*backend.Backend, r *http.Request) *backend.Backend {
+	if len(backends) == 0 {
+		return nil
+	}
+	key := clientIP(r)
+
+	var best *backend.Backend
+	var bestScore uint64
+	for _, b := range backends {
+		score := rendezvousScore(key, b.URL.String())
+		if best == nil || score > bestScore {
+			best = b
+			bestScore = score
+		}
+	}
+	return best
+}
+
+func rendezvousScore(key, node string) uint64 {
+	h := fnv.New64a()
+	h.Write([]byte(key))
+	h.Write([]byte{0})
+	h.Write([]byte(node))
+	return h.Sum64()
+}
+
+func hashKey(key string) uint32 {
+	h := fnv.New32a()
+	h.Write([]byte(key))
+	return h.Sum32()
+}
+
+func clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
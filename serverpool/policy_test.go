@@ -0,0 +1,152 @@
+package serverpool
+
+import (
+	"net/http"
+	"net/url"
+	"testing"
+
+	"github.com/harshit0410/LB/backend"
+)
+
+func testBackend(t *testing.T, rawurl string, weight int) *backend.Backend {
+	t.Helper()
+	u, err := url.Parse(rawurl)
+	if err != nil {
+		t.Fatalf("parse %q: %v", rawurl, err)
+	}
+	b := &backend.Backend{URL: u, Weight: weight}
+	b.SetAlive(true)
+	return b
+}
+
+func testBackends(t *testing.T, n int) []*backend.Backend {
+	t.Helper()
+	backends := make([]*backend.Backend, n)
+	for i := 0; i < n; i++ {
+		backends[i] = testBackend(t, "http://backend-"+string(rune('a'+i))+".local", 1)
+	}
+	return backends
+}
+
+func TestRoundRobinPolicy_Cycles(t *testing.T) {
+	backends := testBackends(t, 3)
+	policy := &RoundRobinPolicy{}
+	req := httpRequest()
+
+	seen := make([]string, 6)
+	for i := range seen {
+		seen[i] = policy.Select(backends, req).URL.String()
+	}
+	for i := 0; i < 3; i++ {
+		if seen[i] != seen[i+3] {
+			t.Fatalf("expected round robin to repeat every %d picks, got %v", 3, seen)
+		}
+	}
+}
+
+func TestLeastConnPolicy_PicksFewestConnections(t *testing.T) {
+	backends := testBackends(t, 3)
+	backends[0].IncConnections()
+	backends[0].IncConnections()
+	backends[1].IncConnections()
+
+	policy := LeastConnPolicy{}
+	got := policy.Select(backends, httpRequest())
+	if got != backends[2] {
+		t.Fatalf("expected backend with zero connections, got %s", got.URL)
+	}
+}
+
+func TestWeightedRoundRobinPolicy_RespectsWeights(t *testing.T) {
+	backends := []*backend.Backend{
+		testBackend(t, "http://a.local", 3),
+		testBackend(t, "http://b.local", 1),
+	}
+	policy := NewWeightedRoundRobinPolicy()
+
+	counts := map[string]int{}
+	const rounds = 400
+	for i := 0; i < rounds; i++ {
+		got := policy.Select(backends, httpRequest())
+		counts[got.URL.String()]++
+	}
+
+	ratio := float64(counts["http://a.local"]) / float64(counts["http://b.local"])
+	if ratio < 2.5 || ratio > 3.5 {
+		t.Fatalf("expected ~3:1 split for weights 3:1, got %v (ratio %.2f)", counts, ratio)
+	}
+}
+
+func TestIPHashPolicy_SameIPSameBackend(t *testing.T) {
+	backends := testBackends(t, 4)
+	policy := IPHashPolicy{}
+
+	r1 := &http.Request{RemoteAddr: "203.0.113.5:51515"}
+	r2 := &http.Request{RemoteAddr: "203.0.113.5:60000"}
+
+	first := policy.Select(backends, r1)
+	second := policy.Select(backends, r2)
+	if first.URL.String() != second.URL.String() {
+		t.Fatalf("expected same client IP to hash to the same backend, got %s and %s", first.URL, second.URL)
+	}
+}
+
+func TestURIHashPolicy_SamePathSameBackend(t *testing.T) {
+	backends := testBackends(t, 4)
+	policy := URIHashPolicy{}
+
+	r1, _ := http.NewRequest("GET", "http://lb.local/foo/bar", nil)
+	r2, _ := http.NewRequest("GET", "http://lb.local/foo/bar?x=1", nil)
+
+	first := policy.Select(backends, r1)
+	second := policy.Select(backends, r2)
+	if first.URL.String() != second.URL.String() {
+		t.Fatalf("expected same path to hash to the same backend, got %s and %s", first.URL, second.URL)
+	}
+}
+
+// TestConsistentHashPolicy_MinimizesReshuffling verifies the rendezvous
+// hash's core property: removing one backend from an N-node pool should
+// only remap roughly 1/N of keys, unlike a modulo hash which remaps
+// nearly all of them.
+func TestConsistentHashPolicy_MinimizesReshuffling(t *testing.T) {
+	const n = 5
+	const sampleSize = 2000
+
+	backends := testBackends(t, n)
+	policy := ConsistentHashPolicy{}
+
+	before := make([]string, sampleSize)
+	for i := 0; i < sampleSize; i++ {
+		req := &http.Request{RemoteAddr: clientAddr(i)}
+		before[i] = policy.Select(backends, req).URL.String()
+	}
+
+	// Drop one backend and recompute over the remaining set.
+	reduced := backends[1:]
+	moved := 0
+	for i := 0; i < sampleSize; i++ {
+		req := &http.Request{RemoteAddr: clientAddr(i)}
+		after := policy.Select(reduced, req).URL.String()
+		if after != before[i] {
+			moved++
+		}
+	}
+
+	moveRatio := float64(moved) / float64(sampleSize)
+	// Removing 1 of 5 backends should remap close to 1/5 of keys. Allow
+	// generous slack since fnv hashing over a small sample isn't perfectly
+	// uniform, but it should be nowhere near "almost everything moved".
+	if moveRatio > 0.35 {
+		t.Fatalf("expected roughly 1/%d of keys to move, moved %.2f%%", n, moveRatio*100)
+	}
+}
+
+func clientAddr(i int) string {
+	return "10.0." + string(rune('0'+(i/250)%10)) + "." + string(rune('0'+i%250)) + ":12345"
+}
+
+func httpRequest() *http.Request {
+	r, _ := http.NewRequest("GET", "http://lb.local/", nil)
+	return r
+}
@@ -0,0 +1,190 @@
+package serverpool
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"sync"
+
+	"github.com/harshit0410/LB/backend"
+	"github.com/harshit0410/LB/healthcheck"
+)
+
+// ServerPool tracks the backends behind the load balancer, the
+// SelectionPolicy used to route requests among them, and the active
+// health-check configuration applied to every backend added to it.
+//
+// backends and healthCfg are mutated by the admin API's goroutines
+// (add/remove/diff a backend) while request-serving goroutines read them
+// concurrently via Select/HealthCheck, so both are guarded by mux.
+type ServerPool struct {
+	mux       sync.RWMutex
+	backends  []*backend.Backend
+	policy    SelectionPolicy
+	healthCfg healthcheck.Config
+}
+
+// NewServerPool builds a ServerPool using the named selection policy.
+// Supported names: "" / "round_robin", "random", "least_conn",
+// "weighted", "ip_hash", "uri_hash", "consistent_hash".
+func NewServerPool(policyName string) (*ServerPool, error) {
+	policy, err := newPolicy(policyName)
+	if err != nil {
+		return nil, err
+	}
+	return &ServerPool{policy: policy, healthCfg: healthcheck.DefaultConfig()}, nil
+}
+
+// SetHealthCheckConfig sets the pool's health-check config, filling in
+// any unspecified field from healthcheck.DefaultConfig. The probe
+// settings apply to every backend without an override from the next
+// HealthCheck round; the circuit-breaker thresholds only apply to
+// breakers built by later AddBackend calls, and backends already in the
+// pool keep the breaker they have.
+func (s *ServerPool) SetHealthCheckConfig(cfg healthcheck.Config) {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	s.healthCfg = cfg.WithDefaults()
+}
+
+func newPolicy(name string) (SelectionPolicy, error) {
+	switch name {
+	case "", "round_robin":
+		return &RoundRobinPolicy{}, nil
+	case "random":
+		return RandomPolicy{}, nil
+	case "least_conn":
+		return LeastConnPolicy{}, nil
+	case "weighted":
+		return NewWeightedRoundRobinPolicy(), nil
+	case "ip_hash":
+		return IPHashPolicy{}, nil
+	case "uri_hash":
+		return URIHashPolicy{}, nil
+	case "consistent_hash":
+		return ConsistentHashPolicy{}, nil
+	default:
+		return nil, fmt.Errorf("serverpool: unknown policy %q", name)
+	}
+}
+
+// AddBackend registers a backend with the pool, giving it a circuit
+// breaker sized from its own health-check override if it has one set via
+// SetHealthConfig, or from the pool's health-check config otherwise, if
+// it doesn't already have a breaker.
+func (s *ServerPool) AddBackend(b *backend.Backend) {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	if b.Breaker == nil {
+		cfg := s.healthCfg
+		if override := b.HealthConfig(); override != nil {
+			cfg = override.WithDefaults()
+		}
+		b.Breaker = backend.NewCircuitBreaker(
+			cfg.UnhealthyThreshold,
+			cfg.HealthyThreshold,
+			cfg.Interval,
+			cfg.Interval,
+		)
+	}
+	s.backends = append(s.backends, b)
+}
+
+// RemoveAllBackend clears every backend from the pool.
+func (s *ServerPool) RemoveAllBackend() {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	s.backends = nil
+}
+
+// RemoveBackend removes the backend matching urlStr, reporting whether
+// one was found.
+func (s *ServerPool) RemoveBackend(urlStr string) bool {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	for i, b := range s.backends {
+		if b.URL.String() == urlStr {
+			s.backends = append(s.backends[:i:i], s.backends[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// FindBackend returns the backend matching urlStr, or nil if none does.
+func (s *ServerPool) FindBackend(urlStr string) *backend.Backend {
+	s.mux.RLock()
+	defer s.mux.RUnlock()
+	for _, b := range s.backends {
+		if b.URL.String() == urlStr {
+			return b
+		}
+	}
+	return nil
+}
+
+// Backends returns a copy of every backend in the pool, alive or not, so
+// callers can range over it without racing a concurrent add/remove.
+func (s *ServerPool) Backends() []*backend.Backend {
+	s.mux.RLock()
+	defer s.mux.RUnlock()
+	out := make([]*backend.Backend, len(s.backends))
+	copy(out, s.backends)
+	return out
+}
+
+// MarkBackendStatus flips the alive flag for the backend matching backendUrl.
+func (s *ServerPool) MarkBackendStatus(backendUrl *url.URL, alive bool) {
+	s.mux.RLock()
+	defer s.mux.RUnlock()
+	for _, b := range s.backends {
+		if b.URL.String() == backendUrl.String() {
+			b.SetAlive(alive)
+			break
+		}
+	}
+}
+
+// Select routes r to a backend chosen by the pool's policy, considering
+// only backends that are marked alive, are not draining, and whose
+// circuit breaker is not open. It returns nil if none qualify.
+func (s *ServerPool) Select(r *http.Request) *backend.Backend {
+	backends := s.Backends()
+	alive := make([]*backend.Backend, 0, len(backends))
+	for _, b := range backends {
+		if b.IsAlive() && !b.IsDraining() && (b.Breaker == nil || b.Breaker.Allow()) {
+			alive = append(alive, b)
+		}
+	}
+	return s.policy.Select(alive, r)
+}
+
+// HealthCheck actively probes every backend and sets its alive status
+// from that one probe's result, using each backend's own health-check
+// override if SetHealthConfig has been called on it, or the pool's
+// config otherwise. UnhealthyThreshold and HealthyThreshold are not
+// applied here; they only size the circuit breaker in AddBackend. It snapshots the
+// backend list and health config up front so a slow probe round doesn't
+// hold the pool lock.
+func (s *ServerPool) HealthCheck() {
+	s.mux.RLock()
+	backends := make([]*backend.Backend, len(s.backends))
+	copy(backends, s.backends)
+	cfg := s.healthCfg
+	s.mux.RUnlock()
+
+	for _, b := range backends {
+		effective := cfg
+		if override := b.HealthConfig(); override != nil {
+			effective = override.WithDefaults()
+		}
+		status := "up"
+		alive := healthcheck.Probe(b.URL, effective)
+		b.SetAlive(alive)
+		if !alive {
+			status = "down"
+		}
+		log.Printf("%s [%s]\n", b.URL, status)
+	}
+}